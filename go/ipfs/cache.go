@@ -0,0 +1,264 @@
+package ipfs
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	log "github.com/eris-ltd/eris-logger"
+)
+
+// cacheKeyPattern restricts cache keys to characters a CID can actually
+// contain (base58/base32/base36 multibase alphabets plus "Qm..." legacy
+// CIDv0s), which also rules out "/", "\", and ".." - rejecting those is
+// what keeps a hash pulled straight off an HTTP request path from escaping
+// Dir via filepath.Join.
+var cacheKeyPattern = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// validCacheKey reports whether hash is safe to use as a path component
+// under Dir.
+func validCacheKey(hash string) bool {
+	return hash != "" && cacheKeyPattern.MatchString(hash)
+}
+
+// cacheEntry tracks one cached object for LRU eviction bookkeeping.
+type cacheEntry struct {
+	hash string
+	size int64
+}
+
+// CachingGateway wraps GetFromIPFS/CatFromIPFS with an on-disk cache keyed
+// by CID. Because IPFS content is content-addressed, a hit never needs
+// invalidation: if the bytes are on disk under a hash, they are the right
+// bytes. Eviction is size-based LRU over MaxBytes.
+type CachingGateway struct {
+	Dir      string
+	MaxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// NewCachingGateway returns a CachingGateway backed by dir, creating it if
+// necessary, that evicts least-recently-used entries once the cache holds
+// more than maxBytes. maxBytes <= 0 means unbounded.
+func NewCachingGateway(dir string, maxBytes int64) (*CachingGateway, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &CachingGateway{
+		Dir:      dir,
+		MaxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}, nil
+}
+
+// path returns the on-disk path hash is cached under, rejecting any hash
+// that isn't a plain CID-shaped string so it can't be used to escape Dir
+// (e.g. via "../" path traversal) when it comes from an untrusted source
+// like an HTTP request.
+func (g *CachingGateway) path(hash string) (string, error) {
+	if !validCacheKey(hash) {
+		return "", fmt.Errorf("invalid cache key %q", hash)
+	}
+	return filepath.Join(g.Dir, hash), nil
+}
+
+// touch marks hash as most-recently-used, recording its size the first
+// time it is seen, and evicts older entries until the cache fits MaxBytes
+// or hash is the only entry left (hash itself is never evicted here).
+func (g *CachingGateway) touch(hash string, size int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if elem, ok := g.entries[hash]; ok {
+		g.order.MoveToFront(elem)
+		return
+	}
+
+	g.entries[hash] = g.order.PushFront(&cacheEntry{hash: hash, size: size})
+	g.size += size
+
+	for g.MaxBytes > 0 && g.size > g.MaxBytes {
+		oldest := g.order.Back()
+		if oldest == nil {
+			break
+		}
+		entry := oldest.Value.(*cacheEntry)
+		if entry.hash == hash {
+			break
+		}
+		g.order.Remove(oldest)
+		delete(g.entries, entry.hash)
+		g.size -= entry.size
+		if entryPath, err := g.path(entry.hash); err == nil {
+			os.Remove(entryPath)
+		}
+		log.WithFields(log.Fields{"hash": entry.hash}).Warn("Evicting object from IPFS cache")
+	}
+}
+
+// verifyCachedFile reads the file at path and checks it against hash's embedded
+// multihash, removing the file on mismatch so a corrupt or malicious fetch
+// is never left under a trusted-looking hash.
+func verifyCachedFile(hash, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := verifyCIDDigest(hash, data); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// Get serves hash from the cache, falling back to GetFromIPFS and caching
+// the result on a miss, then copying it to fileName under dirName. A fetch
+// is verified against hash before being cached or copied out.
+func (g *CachingGateway) Get(hash, fileName, dirName string) error {
+	cachePath, err := g.path(hash)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(cachePath); err == nil {
+		g.touch(hash, info.Size())
+		return copyFile(cachePath, filepath.Join(dirName, fileName))
+	}
+
+	if err := GetFromIPFS(hash, hash, g.Dir); err != nil {
+		os.Remove(cachePath)
+		return err
+	}
+	if err := verifyCachedFile(hash, cachePath); err != nil {
+		return err
+	}
+	info, err := os.Stat(cachePath)
+	if err != nil {
+		return err
+	}
+	g.touch(hash, info.Size())
+
+	return copyFile(cachePath, filepath.Join(dirName, fileName))
+}
+
+// Cat serves fileHash from the cache, falling back to CatFromIPFS and
+// caching the result on a miss. A fetch is verified against fileHash
+// before being cached.
+func (g *CachingGateway) Cat(fileHash string) (string, error) {
+	cachePath, err := g.path(fileHash)
+	if err != nil {
+		return "", err
+	}
+
+	if info, err := os.Stat(cachePath); err == nil {
+		body, err := os.ReadFile(cachePath)
+		if err != nil {
+			return "", err
+		}
+		g.touch(fileHash, info.Size())
+		return string(body), nil
+	}
+
+	body, err := CatFromIPFS(fileHash)
+	if err != nil {
+		return "", err
+	}
+	if err := verifyCIDDigest(fileHash, []byte(body)); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(cachePath, []byte(body), 0600); err != nil {
+		return "", err
+	}
+	g.touch(fileHash, int64(len(body)))
+
+	return body, nil
+}
+
+// Prewarm fetches and caches each hash, and pins it so a batch of prior
+// downloads survives garbage collection.
+func (g *CachingGateway) Prewarm(hashes []string) error {
+	for _, hash := range hashes {
+		if _, err := g.Cat(hash); err != nil {
+			return err
+		}
+		if _, err := PinToIPFS(hash, true); err != nil {
+			return err
+		}
+	}
+	return FlushPins()
+}
+
+// Handler returns an http.Handler that serves cached objects by hash under
+// its root path, fetching and caching on a miss, so other processes on the
+// same host can share the cache over HTTP.
+func (g *CachingGateway) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hash := strings.TrimPrefix(r.URL.Path, "/")
+		if hash == "" {
+			http.Error(w, "missing hash", http.StatusBadRequest)
+			return
+		}
+
+		cachePath, err := g.path(hash)
+		if err != nil {
+			http.Error(w, "invalid hash", http.StatusBadRequest)
+			return
+		}
+
+		if info, err := os.Stat(cachePath); err != nil {
+			if err := GetFromIPFS(hash, hash, g.Dir); err != nil {
+				os.Remove(cachePath)
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			if err := verifyCachedFile(hash, cachePath); err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			info, err = os.Stat(cachePath)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			g.touch(hash, info.Size())
+		} else {
+			g.touch(hash, info.Size())
+		}
+
+		http.ServeFile(w, r, cachePath)
+	})
+}
+
+func copyFile(from, to string) error {
+	if dir := filepath.Dir(to); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, src)
+	return err
+}
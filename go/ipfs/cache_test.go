@@ -0,0 +1,53 @@
+package ipfs
+
+import "testing"
+
+func TestValidCacheKey(t *testing.T) {
+	cases := map[string]bool{
+		"QmYwAPJzv5CZsnA625s3Xf2nemtYgPpHdWEz79ojWnPbdG":             true,
+		"bafybeigdyrzt5sfp7udm7hu76uh7y26nf3efuylqabf3oclgtqy55fbzdi": true,
+		"":                 false,
+		"..":               false,
+		"../../etc/passwd": false,
+		"/tmp/evil":        false,
+		"foo/../../bar":    false,
+		"foo\\bar":         false,
+	}
+
+	for hash, want := range cases {
+		if got := validCacheKey(hash); got != want {
+			t.Errorf("validCacheKey(%q) = %v, want %v", hash, got, want)
+		}
+	}
+}
+
+func TestCachingGatewayPathRejectsTraversal(t *testing.T) {
+	g, err := NewCachingGateway(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := g.path("../../../../tmp/evil"); err == nil {
+		t.Fatal("expected path traversal hash to be rejected")
+	}
+}
+
+func TestCachingGatewayTouchEviction(t *testing.T) {
+	g, err := NewCachingGateway(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g.touch("a", 6)
+	g.touch("b", 6)
+
+	if _, ok := g.entries["a"]; ok {
+		t.Fatal("expected oldest entry to be evicted once MaxBytes is exceeded")
+	}
+	if _, ok := g.entries["b"]; !ok {
+		t.Fatal("expected most recently touched entry to survive eviction")
+	}
+	if g.size != 6 {
+		t.Fatalf("expected cache size to be 6 after eviction, got %d", g.size)
+	}
+}
@@ -0,0 +1,195 @@
+package ipfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/eris-ltd/eris-logger"
+)
+
+type idReply struct {
+	ID string
+}
+
+// WaitForIPFSReady polls `id` on the API URL with exponential backoff,
+// starting at 500ms and capping at 10s, until it gets a reply with a
+// non-empty ID field or ctx is done. Call this before issuing other API
+// calls against a daemon that may still be starting up; without it, callers
+// get the gateway's raw "context deadline exceeded" error instead of a
+// clear readiness failure.
+func WaitForIPFSReady(ctx context.Context) error {
+	return pollReady(ctx, IPFSBaseAPIUrl(), func(url string) ([]byte, error) {
+		return PostAPICall(url, "")
+	})
+}
+
+// pollReady is the backoff loop WaitForIPFSReady and Client.waitReady share;
+// post issues the actual `id` request so a Client can route it through its
+// own Transport instead of always going out over defaultClient.
+func pollReady(ctx context.Context, apiURL string, post func(url string) ([]byte, error)) error {
+	backoff := 500 * time.Millisecond
+	const maxBackoff = 10 * time.Second
+
+	for {
+		body, err := post(apiURL + "id")
+		if err == nil {
+			var out idReply
+			dec := json.NewDecoder(bytes.NewReader(body))
+			if decErr := dec.Decode(&out); decErr == nil && out.ID != "" {
+				return nil
+			}
+		}
+
+		log.WithFields(log.Fields{
+			"backoff": backoff,
+		}).Warn("IPFS daemon not ready yet, retrying")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("IPFS daemon was not ready before context was done: %v", ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// Client holds per-caller IPFS configuration: the gateway/API URLs, a
+// readiness gate, and a pluggable Transport (TCP by default, or a Unix
+// socket) so its calls don't have to go through defaultClient. A Client is
+// safe for concurrent use: httpClient and ready are guarded by clientMu
+// since a shared Client (e.g. defaultClient) is hit concurrently by every
+// public helper.
+type Client struct {
+	GatewayURL string
+	APIURL     string
+	Timeout    time.Duration
+	Transport  Transport
+
+	clientMu   sync.Mutex
+	ready      bool
+	httpClient *http.Client
+}
+
+// NewClient returns a Client configured with eris' default gateway and API
+// URLs and a TCPTransport.
+func NewClient() *Client {
+	return &Client{
+		GatewayURL: IPFSBaseGatewayUrl(""),
+		APIURL:     IPFSBaseAPIUrl(),
+		Timeout:    timeout,
+		Transport:  NewTCPTransport(),
+	}
+}
+
+// NewClientForAPI returns a Client configured to reach apiURL, switching to
+// a UnixSocketTransport automatically when apiURL is a unix:///path/to/sock
+// URL rather than an ordinary http(s) one.
+func NewClientForAPI(apiURL, gatewayURL string) *Client {
+	c := NewClient()
+	c.GatewayURL = gatewayURL
+
+	if IsUnixSocketURL(apiURL) {
+		t := NewUnixSocketTransport(apiURL)
+		c.Transport = t
+		c.APIURL = t.BaseURL()
+		return c
+	}
+
+	c.APIURL = apiURL
+	return c
+}
+
+// HTTPClient returns the *http.Client all of this Client's API calls share,
+// building it from c.Transport on first use.
+func (c *Client) HTTPClient() *http.Client {
+	c.clientMu.Lock()
+	defer c.clientMu.Unlock()
+	if c.httpClient == nil {
+		if c.Transport == nil {
+			c.Transport = NewTCPTransport()
+		}
+		c.httpClient = &http.Client{Transport: c.Transport.RoundTripper()}
+	}
+	return c.httpClient
+}
+
+// waitReady polls c.APIURL through c.HTTPClient(), remembering success so
+// later calls don't pay the polling cost again.
+func (c *Client) waitReady(ctx context.Context) error {
+	c.clientMu.Lock()
+	ready := c.ready
+	c.clientMu.Unlock()
+	if ready {
+		return nil
+	}
+
+	post := func(url string) ([]byte, error) { return postAPICall(c.HTTPClient(), url) }
+	if err := pollReady(ctx, c.APIURL, post); err != nil {
+		return err
+	}
+
+	c.clientMu.Lock()
+	c.ready = true
+	c.clientMu.Unlock()
+	return nil
+}
+
+// postAPICall is PostAPICall's request shape issued through an explicit
+// *http.Client, so a Client's Transport actually applies.
+func postAPICall(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Post(url, "application/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// Get downloads hash to fileName under dirName via c.GatewayURL, waiting
+// for the daemon to become ready first.
+func (c *Client) Get(ctx context.Context, hash, fileName, dirName string) error {
+	if err := c.waitReady(ctx); err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{
+		"file": fileName,
+		"hash": hash,
+	}).Warn("Getting file from IPFS")
+	return downloadFromURLToFile(c.HTTPClient(), c.GatewayURL+hash, fileName, dirName, "", "", "")
+}
+
+// Cat returns the contents of fileHash via c.APIURL, waiting for the
+// daemon to become ready first.
+func (c *Client) Cat(ctx context.Context, fileHash string) (string, error) {
+	if err := c.waitReady(ctx); err != nil {
+		return "", err
+	}
+	body, err := postAPICall(c.HTTPClient(), c.APIURL+"cat?arg="+fileHash)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// List returns the links under objectHash via c.APIURL, waiting for the
+// daemon to become ready first.
+func (c *Client) List(ctx context.Context, objectHash string) (string, error) {
+	if err := c.waitReady(ctx); err != nil {
+		return "", err
+	}
+	body, err := postAPICall(c.HTTPClient(), c.APIURL+"ls?arg="+objectHash)
+	if err != nil {
+		return "", err
+	}
+	return decodeLsReply(body)
+}
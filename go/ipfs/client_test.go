@@ -0,0 +1,40 @@
+package ipfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPollReadySucceedsOnceIDIsNonEmpty(t *testing.T) {
+	calls := 0
+	post := func(url string) ([]byte, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("connection refused")
+		}
+		return []byte(`{"ID":"Qmself"}`), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := pollReady(ctx, "http://127.0.0.1:5001/api/v0/", post); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls before success, got %d", calls)
+	}
+}
+
+func TestPollReadyReturnsErrorWhenContextDone(t *testing.T) {
+	post := func(url string) ([]byte, error) { return nil, errors.New("connection refused") }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := pollReady(ctx, "http://127.0.0.1:5001/api/v0/", post); err == nil {
+		t.Fatal("expected an error once the context is done")
+	}
+}
@@ -0,0 +1,135 @@
+package ipfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	log "github.com/eris-ltd/eris-logger"
+)
+
+type objectStat struct {
+	Hash string
+}
+
+// resolveObject calls `object/stat` on hash and returns the resolved CID, or
+// an error if the object cannot be found.
+func resolveObject(hash string) (string, error) {
+	url := IPFSBaseAPIUrl() + "object/stat?arg=" + hash
+	body, err := PostAPICall(url, hash)
+	if err != nil {
+		return "", fmt.Errorf("could not resolve object %s: %v", hash, err)
+	}
+	return decodeObjectStat(body, hash)
+}
+
+// decodeObjectStat parses an `object/stat` reply, pulled out of
+// resolveObject so it can be tested without a daemon.
+func decodeObjectStat(body []byte, hash string) (string, error) {
+	var out objectStat
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if err := dec.Decode(&out); err != nil {
+		return "", fmt.Errorf("could not parse object/stat reply for %s: %v", hash, err)
+	}
+	if out.Hash == "" {
+		return "", fmt.Errorf("object %s not found", hash)
+	}
+	return out.Hash, nil
+}
+
+type pinReply struct {
+	Pins []string
+}
+
+// decodePinReply parses a `pin/add` or `pin/rm` reply, pulled out of
+// PinToIPFS and UnpinFromIPFS so it can be tested without a daemon.
+func decodePinReply(body []byte) ([]string, error) {
+	var out pinReply
+	dec := json.NewDecoder(bytes.NewReader(body))
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	return out.Pins, nil
+}
+
+// PinToIPFS pins hash, recursively if recursive is true, and returns the
+// resolved CIDs that were pinned.
+func PinToIPFS(hash string, recursive bool) ([]string, error) {
+	resolved, err := resolveObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%spin/add?arg=%s&recursive=%t", IPFSBaseAPIUrl(), resolved, recursive)
+	log.WithFields(log.Fields{
+		"hash":      resolved,
+		"recursive": recursive,
+	}).Warn("Pinning object in IPFS")
+
+	body, err := PostAPICall(url, resolved)
+	if err != nil {
+		return nil, err
+	}
+	return decodePinReply(body)
+}
+
+// UnpinFromIPFS unpins hash, recursively if recursive is true, and returns
+// the resolved CIDs that were unpinned.
+func UnpinFromIPFS(hash string, recursive bool) ([]string, error) {
+	resolved, err := resolveObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%spin/rm?arg=%s&recursive=%t", IPFSBaseAPIUrl(), resolved, recursive)
+	log.WithFields(log.Fields{
+		"hash":      resolved,
+		"recursive": recursive,
+	}).Warn("Unpinning object in IPFS")
+
+	body, err := PostAPICall(url, resolved)
+	if err != nil {
+		return nil, err
+	}
+	return decodePinReply(body)
+}
+
+// PinUpdateIPFS moves a pin from one CID to another in a single operation,
+// which is cheaper than a separate pin/unpin pair because the daemon can
+// reuse the shared DAG structure between from and to. If unpin is false the
+// old pin at from is left in place.
+func PinUpdateIPFS(from, to string, unpin bool) error {
+	resolvedFrom, err := resolveObject(from)
+	if err != nil {
+		return err
+	}
+	resolvedTo, err := resolveObject(to)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%spin/update?arg=%s&arg=%s&unpin=%t", IPFSBaseAPIUrl(), resolvedFrom, resolvedTo, unpin)
+	log.WithFields(log.Fields{
+		"from":  resolvedFrom,
+		"to":    resolvedTo,
+		"unpin": unpin,
+	}).Warn("Updating pin in IPFS")
+
+	_, err = PostAPICall(url, resolvedTo)
+	return err
+}
+
+// FlushPins asks the daemon to verify the pinset and run garbage collection,
+// so that callers can commit and verify pins after a batch of pin/unpin
+// operations.
+func FlushPins() error {
+	log.Warn("Flushing and verifying pinset")
+
+	if _, err := PostAPICall(IPFSBaseAPIUrl()+"pin/verify", ""); err != nil {
+		return fmt.Errorf("could not verify pinset: %v", err)
+	}
+	if _, err := PostAPICall(IPFSBaseAPIUrl()+"repo/gc", ""); err != nil {
+		return fmt.Errorf("could not run repo gc: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package ipfs
+
+import "testing"
+
+func TestDecodeObjectStat(t *testing.T) {
+	hash, err := decodeObjectStat([]byte(`{"Hash":"QmResolved"}`), "QmOriginal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != "QmResolved" {
+		t.Fatalf("got %q, want %q", hash, "QmResolved")
+	}
+
+	if _, err := decodeObjectStat([]byte(`{"Hash":""}`), "QmOriginal"); err == nil {
+		t.Fatal("expected error for an empty Hash")
+	}
+
+	if _, err := decodeObjectStat([]byte(`not json`), "QmOriginal"); err == nil {
+		t.Fatal("expected error for unparseable body")
+	}
+}
+
+func TestDecodePinReply(t *testing.T) {
+	pins, err := decodePinReply([]byte(`{"Pins":["QmA","QmB"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 2 || pins[0] != "QmA" || pins[1] != "QmB" {
+		t.Fatalf("got %v, want [QmA QmB]", pins)
+	}
+
+	if _, err := decodePinReply([]byte(`not json`)); err == nil {
+		t.Fatal("expected error for unparseable body")
+	}
+}
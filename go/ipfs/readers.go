@@ -2,11 +2,11 @@ package ipfs
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -23,7 +23,37 @@ func GetFromIPFS(hash, fileName, dirName string) error {
 		"file": fileName,
 		"hash": hash,
 	}).Warn("Getting file from IPFS")
-	return DownloadFromUrlToFile(url, fileName, dirName, "") // no proxy for IPFS ?
+	return DownloadFromUrlToFile(url, fileName, dirName, "", "", "") // no proxy for IPFS, no digest to check
+}
+
+// GetFromIPFSVerified downloads hash the same way GetFromIPFS does, then
+// decodes the multihash embedded in hash and verifies the downloaded bytes
+// against it, deleting the file on a mismatch. Verification goes through
+// go-multihash's own codec registry, so it covers any multihash codec a
+// CIDv1 may carry, not just the digest algorithms DownloadFromUrlToFile
+// supports for plain hex-digest callers.
+func GetFromIPFSVerified(hash, fileName, dirName string) error {
+	url := IPFSBaseGatewayUrl("") + hash
+	log.WithFields(log.Fields{
+		"file": fileName,
+		"hash": hash,
+	}).Warn("Getting and verifying file from IPFS")
+
+	if err := DownloadFromUrlToFile(url, fileName, dirName, "", "", ""); err != nil {
+		return err
+	}
+
+	_, endPath := finalPathFor(url, fileName, dirName)
+	data, err := ioutil.ReadFile(endPath)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyCIDDigest(hash, data); err != nil {
+		os.Remove(endPath)
+		return err
+	}
+	return nil
 }
 
 func CatFromIPFS(fileHash string) (string, error) {
@@ -46,8 +76,15 @@ func ListFromIPFS(objectHash string) (string, error) {
 		"hash": objectHash,
 	}).Warn("Listing file from IPFS")
 	body, err := PostAPICall(url, objectHash)
-	r := bytes.NewReader(body)
+	if err != nil {
+		return "", err
+	}
+	return decodeLsReply(body)
+}
 
+// decodeLsReply parses the `ls` JSON reply shared by ListFromIPFS and
+// Client.List into "<hash> <name>" lines.
+func decodeLsReply(body []byte) (string, error) {
 	type LsLink struct {
 		Name, Hash string
 		Size       uint64
@@ -57,10 +94,9 @@ func ListFromIPFS(objectHash string) (string, error) {
 		Links []LsLink
 	}
 
-	dec := json.NewDecoder(r)
+	dec := json.NewDecoder(bytes.NewReader(body))
 	out := struct{ Objects []LsObject }{}
-	err = dec.Decode(&out)
-	if err != nil {
+	if err := dec.Decode(&out); err != nil {
 		return "", err
 	}
 	contents := out.Objects[0].Links
@@ -69,8 +105,7 @@ func ListFromIPFS(objectHash string) (string, error) {
 	for i, c := range contents {
 		res[i] = c.Hash + " " + c.Name
 	}
-	result := strings.Join(res, "\n")
-	return result, nil
+	return strings.Join(res, "\n"), nil
 }
 
 func ListPinnedFromIPFS() (string, error) {
@@ -106,33 +141,40 @@ func ListPinnedFromIPFS() (string, error) {
 	return result, nil
 }
 
-func DownloadFromUrlToFile(url0, fileName, dirName, proxyURL string) error {
-	tokens := strings.Split(url0, "/")
-	if fileName == "" {
-		fileName = tokens[len(tokens)-1]
-	}
+// DownloadFromUrlToFile downloads url0 to fileName under dirName. If
+// expectedDigest is non-empty, the response is streamed through a hasher
+// for digestAlgo ("sha256" or "sha512") and the computed digest is compared
+// against expectedDigest (hex-encoded); on a mismatch the partial file is
+// removed and ErrDigestMismatch is returned. This lets any caller, IPFS or
+// not, assert content integrity the way the parameter-fetch helpers Filecoin
+// uses for its trusted-setup files do.
+func DownloadFromUrlToFile(url0, fileName, dirName, proxyURL, expectedDigest, digestAlgo string) error {
+	return downloadFromURLToFile(defaultClient.HTTPClient(), url0, fileName, dirName, proxyURL, expectedDigest, digestAlgo)
+}
 
-	//use absolute paths?
-	endPath := path.Join(dirName, fileName)
+// downloadFromURLToFile is what DownloadFromUrlToFile and Client.Get
+// actually run, parameterized on the *http.Client to use so a Client's own
+// Transport (e.g. a Unix-socket one) is honored instead of always going out
+// over defaultClient.
+func downloadFromURLToFile(client *http.Client, url0, fileName, dirName, proxyURL, expectedDigest, digestAlgo string) error {
+	fileName, endPath := finalPathFor(url0, fileName, dirName)
 	if dirName != "" {
 		log.WithFields(log.Fields{
-			"from": url,
+			"from": url0,
 			"to":   endPath,
 		}).Warn("Downloading")
 		checkDir, err := os.Stat(dirName)
 		if err != nil {
 			log.Warn("Directory does not exist, creating it")
-			err1 := os.MkdirAll(dirName, 0700)
-			if err1 != nil {
+			if err1 := os.MkdirAll(dirName, 0700); err1 != nil {
 				return fmt.Errorf("error making directory, check your permissions %v\n", err1)
 			}
-		}
-		if !checkDir.IsDir() {
+		} else if !checkDir.IsDir() {
 			return fmt.Errorf("path specified is not a directory, please enter a directory")
 		}
 	} else {
 		log.WithFields(log.Fields{
-			"from": url,
+			"from": url0,
 			"to":   fileName,
 		}).Warn("Downloading")
 	}
@@ -154,22 +196,14 @@ func DownloadFromUrlToFile(url0, fileName, dirName, proxyURL string) error {
 		defer outputFile.Close()
 	}
 
-	transport := http.Transport{Dial: dialTimeout}
-
-	if proxyURL == "" {
-		transport = http.Transport{Proxy: nil}
-	} else {
-		urli := url.URL{}
-		urlProxy, err := urli.Parse(proxyURL)
+	// Reuse the caller's client for keep-alives; only build a one-off
+	// transport when a per-call proxy is requested.
+	if proxyURL != "" {
+		urlProxy, err := url.Parse(proxyURL)
 		if err != nil {
 			return err
 		}
-		transport = http.Transport{Proxy: http.ProxyURL(urlProxy)}
-	}
-
-	// adding manual timeouts as IPFS hangs for a while
-	client := http.Client{
-		Transport: &transport,
+		client = &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(urlProxy)}}
 	}
 
 	response, err := client.Get(url0)
@@ -178,25 +212,38 @@ func DownloadFromUrlToFile(url0, fileName, dirName, proxyURL string) error {
 	}
 	defer response.Body.Close()
 
-	var checkBody []byte
-	if dirName != "" {
-		_, err = io.Copy(outputInDir, response.Body)
-		if err != nil {
-			return err
-		}
-		checkBody, err = ioutil.ReadFile(endPath)
-		if err != nil {
-			return err
+	algo := ""
+	if expectedDigest != "" {
+		if digestAlgo == "" {
+			return fmt.Errorf("no digest algorithm given for expected digest %s", expectedDigest)
 		}
+		algo = digestAlgo
+	}
+	hasher, err := newDigestHasher(algo)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer
+	var checkPath string
+	if dirName != "" {
+		out = outputInDir
+		checkPath = endPath
 	} else {
-		_, err = io.Copy(outputFile, response.Body)
-		if err != nil {
-			return err
-		}
-		checkBody, err = ioutil.ReadFile(fileName)
-		if err != nil {
-			return err
-		}
+		out = outputFile
+		checkPath = fileName
+	}
+	if hasher != nil {
+		out = io.MultiWriter(out, hasher)
+	}
+
+	if _, err = io.Copy(out, response.Body); err != nil {
+		return err
+	}
+
+	checkBody, err := ioutil.ReadFile(checkPath)
+	if err != nil {
+		return err
 	}
 
 	//deal with ipfs' error ungracefully. maybe we want to maintain our own fork?
@@ -206,6 +253,14 @@ func DownloadFromUrlToFile(url0, fileName, dirName, proxyURL string) error {
 		return fmt.Errorf("A timeout occured while trying to reach IPFS. Run `eris files cache [hash], wait 5-10 seconds, then run `eris files [cmd] [hash]`")
 	}
 
+	if hasher != nil {
+		computed := hex.EncodeToString(hasher.Sum(nil))
+		if computed != expectedDigest {
+			os.Remove(checkPath)
+			return &ErrDigestMismatch{Expected: expectedDigest, Computed: computed, Algo: digestAlgo}
+		}
+	}
+
 	return nil
 }
 
@@ -214,6 +269,18 @@ func DownloadFromUrlToFile(url0, fileName, dirName, proxyURL string) error {
 
 var timeout = time.Duration(10 * time.Second)
 
-func dialTimeout(network, addr string) (net.Conn, error) {
-	return net.DialTimeout(network, addr, timeout)
+// defaultClient is the Client all package-level helpers share so their
+// connections and keep-alives are reused instead of rebuilt per call.
+var defaultClient = NewClient()
+
+// finalPathFor derives the file name DownloadFromUrlToFile writes to when
+// fileName is left blank (the last path segment of url0) and the full path
+// under dirName, so other helpers that need to know where a download
+// landed don't duplicate this logic.
+func finalPathFor(url0, fileName, dirName string) (resolvedFileName, endPath string) {
+	if fileName == "" {
+		tokens := strings.Split(url0, "/")
+		fileName = tokens[len(tokens)-1]
+	}
+	return fileName, path.Join(dirName, fileName)
 }
@@ -0,0 +1,91 @@
+package ipfs
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tv42/httpunix"
+)
+
+// Transport builds the http.RoundTripper a Client uses to talk to the
+// daemon. Implementations exist for plain TCP and for the Unix-socket API
+// endpoint go-ipfs exposes when run with --unix-socket.
+type Transport interface {
+	RoundTripper() http.RoundTripper
+}
+
+// TCPTransport is the default Transport: a standard *http.Transport with
+// configurable dial, read, and idle timeouts, reused across calls so
+// keep-alives actually work instead of paying a fresh handshake every time.
+type TCPTransport struct {
+	DialTimeout         time.Duration
+	ResponseTimeout     time.Duration
+	IdleConnTimeout     time.Duration
+	MaxIdleConnsPerHost int
+}
+
+// NewTCPTransport returns a TCPTransport with sensible defaults for an IPFS
+// daemon, which can hang for a while under load.
+func NewTCPTransport() *TCPTransport {
+	return &TCPTransport{
+		DialTimeout:         10 * time.Second,
+		ResponseTimeout:     0, // IPFS can legitimately take a long time to answer
+		IdleConnTimeout:     90 * time.Second,
+		MaxIdleConnsPerHost: 8,
+	}
+}
+
+func (t *TCPTransport) RoundTripper() http.RoundTripper {
+	return &http.Transport{
+		Dial: (&net.Dialer{
+			Timeout: t.DialTimeout,
+		}).Dial,
+		ResponseHeaderTimeout: t.ResponseTimeout,
+		IdleConnTimeout:       t.IdleConnTimeout,
+		MaxIdleConnsPerHost:   t.MaxIdleConnsPerHost,
+	}
+}
+
+// unixSocketScheme is the URL scheme unix socket API endpoints use, e.g.
+// unix:///path/to/ipfs.sock.
+const unixSocketScheme = "unix://"
+
+// UnixSocketTransport routes API calls over a Unix domain socket, which is
+// how go-ipfs exposes its API when started with --unix-socket.
+type UnixSocketTransport struct {
+	SocketPath string
+	location   string
+}
+
+// NewUnixSocketTransport returns a Transport that dials socketPath, which
+// may optionally be given in unix:///path/to/sock form.
+func NewUnixSocketTransport(socketPath string) *UnixSocketTransport {
+	return &UnixSocketTransport{
+		SocketPath: strings.TrimPrefix(socketPath, unixSocketScheme),
+		location:   "ipfs-api-socket",
+	}
+}
+
+func (t *UnixSocketTransport) RoundTripper() http.RoundTripper {
+	u := &httpunix.Transport{
+		DialTimeout:           10 * time.Second,
+		RequestTimeout:        0,
+		ResponseHeaderTimeout: 0,
+	}
+	u.RegisterLocation(t.location, t.SocketPath)
+	return u
+}
+
+// BaseURL returns the pseudo-URL httpunix expects API calls to be made
+// against: http+unix://<location>/.
+func (t *UnixSocketTransport) BaseURL() string {
+	return "http+unix://" + t.location + "/"
+}
+
+// IsUnixSocketURL reports whether rawURL names a Unix-socket API endpoint
+// (unix:///path/to/ipfs.sock), as opposed to an ordinary http(s) one.
+func IsUnixSocketURL(rawURL string) bool {
+	return strings.HasPrefix(rawURL, unixSocketScheme)
+}
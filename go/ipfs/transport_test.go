@@ -0,0 +1,48 @@
+package ipfs
+
+import "testing"
+
+func TestIsUnixSocketURL(t *testing.T) {
+	cases := map[string]bool{
+		"unix:///var/run/ipfs.sock": true,
+		"http://127.0.0.1:5001":     false,
+		"https://ipfs.example.com":  false,
+		"":                          false,
+	}
+
+	for url, want := range cases {
+		if got := IsUnixSocketURL(url); got != want {
+			t.Errorf("IsUnixSocketURL(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
+
+func TestUnixSocketTransportBaseURL(t *testing.T) {
+	transport := NewUnixSocketTransport("unix:///var/run/ipfs.sock")
+	if got, want := transport.BaseURL(), "http+unix://ipfs-api-socket/"; got != want {
+		t.Errorf("BaseURL() = %q, want %q", got, want)
+	}
+}
+
+func TestNewClientForAPIUsesUnixSocketTransport(t *testing.T) {
+	c := NewClientForAPI("unix:///var/run/ipfs.sock", "http://127.0.0.1:8080/ipfs/")
+	if _, ok := c.Transport.(*UnixSocketTransport); !ok {
+		t.Fatalf("expected a *UnixSocketTransport, got %T", c.Transport)
+	}
+	if c.APIURL != "http+unix://ipfs-api-socket/" {
+		t.Errorf("APIURL = %q, want %q", c.APIURL, "http+unix://ipfs-api-socket/")
+	}
+	if c.GatewayURL != "http://127.0.0.1:8080/ipfs/" {
+		t.Errorf("GatewayURL = %q, want %q", c.GatewayURL, "http://127.0.0.1:8080/ipfs/")
+	}
+}
+
+func TestNewClientForAPIUsesTCPTransportForHTTP(t *testing.T) {
+	c := NewClientForAPI("http://127.0.0.1:5001/api/v0/", "http://127.0.0.1:8080/ipfs/")
+	if _, ok := c.Transport.(*TCPTransport); !ok {
+		t.Fatalf("expected a *TCPTransport, got %T", c.Transport)
+	}
+	if c.APIURL != "http://127.0.0.1:5001/api/v0/" {
+		t.Errorf("APIURL = %q, want %q", c.APIURL, "http://127.0.0.1:5001/api/v0/")
+	}
+}
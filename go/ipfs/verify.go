@@ -0,0 +1,73 @@
+package ipfs
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+)
+
+// ErrDigestMismatch is returned when downloaded content does not hash to
+// the digest it was expected to, so callers can tell a corrupt or
+// malicious download apart from any other I/O error and retry against a
+// different peer or gateway.
+type ErrDigestMismatch struct {
+	Expected string
+	Computed string
+	Algo     string
+}
+
+func (e *ErrDigestMismatch) Error() string {
+	return fmt.Sprintf("digest mismatch (%s): expected %s, got %s", e.Algo, e.Expected, e.Computed)
+}
+
+// newDigestHasher returns a hash.Hash for the named algorithm, or nil if
+// algo is empty, meaning the caller asked for no verification.
+func newDigestHasher(algo string) (hash.Hash, error) {
+	switch algo {
+	case "":
+		return nil, nil
+	case "sha256", "sha2-256":
+		return sha256.New(), nil
+	case "sha512", "sha2-512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported digest algorithm %q", algo)
+	}
+}
+
+// verifyCIDDigest decodes hashStr as a CID and recomputes its multihash
+// over data, using go-multihash's own codec registry rather than a
+// hand-picked set of algorithms, so it verifies CIDv1 objects hashed with
+// any multihash codec go-multihash knows how to sum (sha2, sha3, blake2b,
+// blake2s, ...), not just sha256/sha512. CIDv0 hashes are always sha2-256.
+func verifyCIDDigest(hashStr string, data []byte) error {
+	c, err := cid.Decode(hashStr)
+	if err != nil {
+		return fmt.Errorf("could not decode CID %s: %v", hashStr, err)
+	}
+
+	decoded, err := multihash.Decode(c.Hash())
+	if err != nil {
+		return fmt.Errorf("could not decode multihash for %s: %v", hashStr, err)
+	}
+
+	computed, err := multihash.Sum(data, decoded.Code, len(decoded.Digest))
+	if err != nil {
+		return fmt.Errorf("cannot verify %s: multihash codec %s not supported: %v", hashStr, decoded.Name, err)
+	}
+
+	if !bytes.Equal(computed, c.Hash()) {
+		return &ErrDigestMismatch{
+			Expected: hex.EncodeToString(c.Hash()),
+			Computed: hex.EncodeToString(computed),
+			Algo:     decoded.Name,
+		}
+	}
+	return nil
+}
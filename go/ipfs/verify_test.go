@@ -0,0 +1,51 @@
+package ipfs
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	mh "github.com/multiformats/go-multihash"
+)
+
+func TestVerifyCIDDigest(t *testing.T) {
+	data := []byte("hello ipfs")
+
+	cases := []struct {
+		name string
+		code uint64
+	}{
+		{"sha2-256", mh.SHA2_256},
+		{"sha2-512", mh.SHA2_512},
+		{"sha3-256", mh.SHA3_256},
+		{"blake2b-256", mh.BLAKE2B_MIN + 31},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sum, err := mh.Sum(data, c.code, -1)
+			if err != nil {
+				t.Skipf("codec %d not available in this build: %v", c.code, err)
+			}
+			id := cid.NewCidV1(cid.Raw, sum)
+
+			if err := verifyCIDDigest(id.String(), data); err != nil {
+				t.Fatalf("expected matching digest to verify, got: %v", err)
+			}
+			if err := verifyCIDDigest(id.String(), []byte("different content")); err == nil {
+				t.Fatal("expected digest mismatch for different content")
+			}
+		})
+	}
+}
+
+func TestNewDigestHasher(t *testing.T) {
+	if h, err := newDigestHasher(""); h != nil || err != nil {
+		t.Fatalf("empty algo should return (nil, nil), got (%v, %v)", h, err)
+	}
+	if h, err := newDigestHasher("sha256"); h == nil || err != nil {
+		t.Fatalf("sha256 should be supported, got (%v, %v)", h, err)
+	}
+	if _, err := newDigestHasher("bogus"); err == nil {
+		t.Fatal("expected error for unsupported algorithm")
+	}
+}
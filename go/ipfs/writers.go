@@ -0,0 +1,196 @@
+package ipfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/eris-ltd/eris-logger"
+)
+
+// AddOptions controls how AddToIPFS walks and uploads a path. It mirrors
+// the flags the ipfs commands package's `ParseArgs` recognizes for `add`.
+type AddOptions struct {
+	Recursive bool   // descend into directories
+	Hidden    bool   // include dotfiles
+	Wrap      bool   // wrap the result in a directory named after path
+	Chunker   string // e.g. "size-262144" or "rabin"; "" uses the daemon default
+	RawLeaves bool   // store leaf nodes as raw blocks instead of unixfs
+	Pin       bool   // pin the result once added
+
+	// Progress, if non-nil, receives one AddProgress per line of the
+	// newline-delimited JSON the daemon streams back, including
+	// intermediate chunking updates, so callers can render a progress bar.
+	Progress chan<- AddProgress
+}
+
+// AddedObject is one file or directory the daemon finished adding.
+type AddedObject struct {
+	Name string
+	Hash string
+	Size int64
+}
+
+// AddProgress is one line of the daemon's streamed add progress: either an
+// in-progress byte count for the file currently being chunked (Hash empty)
+// or a finished object (Hash set).
+type AddProgress struct {
+	Name  string
+	Bytes int64
+	Hash  string
+}
+
+// AddToIPFS walks path (a file or, with opts.Recursive, a directory) and
+// streams it to the daemon's `add` endpoint as a multipart POST, piping the
+// multipart body in from a goroutine rather than buffering it, and returns
+// the added objects in the order the daemon reports them, wrapped root CID
+// last.
+func AddToIPFS(path string, opts AddOptions) ([]AddedObject, error) {
+	// AddToIPFS is the only sender on opts.Progress, so it owns closing it;
+	// without this a caller ranging over the channel would block forever.
+	if opts.Progress != nil {
+		defer close(opts.Progress)
+	}
+
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	go func() {
+		err := addPathToMultipart(writer, path, opts)
+		if err == nil {
+			err = writer.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	url := fmt.Sprintf(
+		"%sadd?stream-channels=true&progress=true&recursive=%t&hidden=%t&wrap-with-directory=%t&raw-leaves=%t&pin=%t",
+		IPFSBaseAPIUrl(), opts.Recursive, opts.Hidden, opts.Wrap, opts.RawLeaves, opts.Pin,
+	)
+	if opts.Chunker != "" {
+		url += "&chunker=" + opts.Chunker
+	}
+
+	log.WithFields(log.Fields{
+		"path":      path,
+		"recursive": opts.Recursive,
+	}).Warn("Adding path to IPFS")
+
+	req, err := http.NewRequest("POST", url, pr)
+	if err != nil {
+		pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := defaultClient.HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// AddToIPFS is the only sender on opts.Progress, so it owns closing it;
+	// without this a caller ranging over the channel would block forever.
+	if opts.Progress != nil {
+		defer close(opts.Progress)
+	}
+
+	var added []AddedObject
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var progress AddProgress
+		if err := dec.Decode(&progress); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return added, err
+		}
+
+		if opts.Progress != nil {
+			opts.Progress <- progress
+		}
+		if progress.Hash != "" {
+			added = append(added, AddedObject{
+				Name: progress.Name,
+				Hash: progress.Hash,
+				Size: progress.Bytes,
+			})
+		}
+	}
+
+	return added, nil
+}
+
+// addPathToMultipart walks path and writes each file (and, if
+// opts.Recursive, each directory) as a part of writer, using the same
+// "abspath" relative-path form and hidden-file filtering that the ipfs
+// commands package's `ParseArgs` applies.
+func addPathToMultipart(writer *multipart.Writer, path string, opts AddOptions) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() && !opts.Recursive {
+		return fmt.Errorf("%s is a directory, pass AddOptions{Recursive: true} to add it", path)
+	}
+
+	base := filepath.Dir(path)
+	return filepath.Walk(path, func(walked string, walkedInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := walked
+		if !opts.Hidden && isHidden(walkedInfo.Name()) {
+			if walkedInfo.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(base, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		contentType := "application/octet-stream"
+		if walkedInfo.IsDir() {
+			contentType = "application/x-directory"
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, rel))
+		header.Set("Content-Type", contentType)
+		header.Set("Abspath", name)
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return err
+		}
+
+		if walkedInfo.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		_, err = io.Copy(part, f)
+		return err
+	})
+}
+
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "."
+}
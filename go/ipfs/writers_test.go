@@ -0,0 +1,20 @@
+package ipfs
+
+import "testing"
+
+func TestIsHidden(t *testing.T) {
+	cases := map[string]bool{
+		".":         false,
+		"..":        true,
+		".git":      true,
+		"file.txt":  false,
+		".dotfile":  true,
+		"normaldir": false,
+	}
+
+	for name, want := range cases {
+		if got := isHidden(name); got != want {
+			t.Errorf("isHidden(%q) = %v, want %v", name, got, want)
+		}
+	}
+}